@@ -19,6 +19,7 @@ import (
 	"bytes"
 	"fmt"
 	"strconv"
+	"strings"
 
 	"github.com/pkg/errors"
 	"github.com/pulumi/go-yaml/ast"
@@ -35,20 +36,42 @@ const indentSpaces = 2 // Set the indent size for the YAML doc generated by the
 // FileAST manages an Abstract Syntax Tree (AST) for YAML configuration files.
 type FileAST struct {
 	ast *ast.File
+
+	// Repairs records the automatic fix-ups NewFileAST made to parse a malformed document, in
+	// the order they were applied. It is empty for a document that parsed cleanly.
+	Repairs []RepairNote
+
+	// tail holds the trailing portion of a malformed document that repairYAML could not make
+	// parseable even after repair, preserved verbatim so Marshal doesn't silently drop it.
+	tail string
 }
 
-// NewFileAST initializes the AST from the given input bytes.
+// NewFileAST initializes the AST from the given input bytes. If the bytes don't parse as
+// well-formed YAML, NewFileAST attempts a best-effort repair pass -- closing dangling flow
+// collections, filling in a placeholder value after a trailing ":", and as a last resort
+// truncating the final incomplete line -- before giving up, similar to the tolerant recovery
+// gopls' cache applies to source files with unclosed braces near EOF. This unblocks commands like
+// `pulumi config set` when a user has a half-typed YAML file, instead of failing outright on a
+// parse error.
 func NewFileAST(yamlBytes []byte) (*FileAST, error) {
 	if yamlBytes == nil {
 		return &FileAST{}, nil
 	}
 
 	fileAST, err := parser.ParseBytes(yamlBytes, parser.ParseComments)
-	if err != nil {
+	if err == nil {
+		return &FileAST{ast: fileAST}, nil
+	}
+
+	repaired, notes, tail := repairYAML(yamlBytes)
+	fileAST, repairErr := parser.ParseBytes(repaired, parser.ParseComments)
+	if repairErr != nil {
+		// The repair pass didn't produce a parseable document either; surface the original
+		// error, since it describes the actual problem in the user's file.
 		return nil, errors.Wrap(err, "failed to parse YAML file")
 	}
 
-	return &FileAST{ast: fileAST}, nil
+	return &FileAST{ast: fileAST, Repairs: notes, tail: tail}, nil
 }
 
 // IsEmpty checks for an uninitialized or empty AST.
@@ -70,7 +93,9 @@ func (f *FileAST) HasKey(k string) bool {
 	return false
 }
 
-// Marshal converts the AST to YAML.
+// Marshal converts the AST to YAML. If NewFileAST had to truncate an unparseable tail to recover
+// the rest of the document, that tail is preserved verbatim in a trailing comment block rather
+// than silently dropped.
 func (f *FileAST) Marshal() []byte {
 	out := bytes.Buffer{}
 	var p printer.Printer
@@ -78,6 +103,15 @@ func (f *FileAST) Marshal() []byte {
 		out.Write(p.PrintNode(d))
 	}
 
+	if f.tail != "" {
+		out.WriteString("\n# The following lines could not be parsed and were preserved as-is:\n")
+		for _, line := range strings.Split(f.tail, "\n") {
+			out.WriteString("# ")
+			out.WriteString(line)
+			out.WriteString("\n")
+		}
+	}
+
 	return out.Bytes()
 }
 
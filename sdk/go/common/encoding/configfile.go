@@ -0,0 +1,63 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package encoding
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/pulumi/pulumi/sdk/v2/go/common/resource/config"
+)
+
+// ConfigFile is the common interface implemented by every supported stack config backend --
+// YAML, JSON, TOML, and HCL -- exposing the subset of operations the CLI needs to read and edit
+// a `Pulumi.<stack>.yaml` file (or its JSON/TOML/HCL equivalent) in place.
+type ConfigFile interface {
+	// HasKey returns true if the key exists on the root document, false otherwise.
+	HasKey(k string) bool
+	// SetConfig sets the value for a given key. If path is true, the key's name portion is
+	// treated as a property path.
+	SetConfig(rootKey string, key config.Key, value config.Value, path bool) error
+	// RemoveConfig removes the value for a given key. If path is true, the key's name portion is
+	// treated as a property path.
+	RemoveConfig(rootKey string, k config.Key, path bool) error
+	// Marshal serializes the document back to bytes in its native format.
+	Marshal() []byte
+	// IsEmpty checks for an uninitialized or empty document.
+	IsEmpty() bool
+}
+
+var (
+	_ ConfigFile = (*FileAST)(nil)
+	_ ConfigFile = (*jsonConfigFile)(nil)
+	_ ConfigFile = (*tomlConfigFile)(nil)
+	_ ConfigFile = (*hclConfigFile)(nil)
+)
+
+// NewConfigFile parses bytes into a ConfigFile, choosing the backend by the file extension of
+// path: ".json" for JSON, ".toml" for TOML, and ".hcl" for HCL. Any other extension (including
+// the usual ".yaml"/".yml") is parsed as YAML, preserving the existing behavior of NewFileAST.
+func NewConfigFile(path string, bytes []byte) (ConfigFile, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return newJSONConfigFile(bytes)
+	case ".toml":
+		return newTOMLConfigFile(bytes)
+	case ".hcl":
+		return newHCLConfigFile(bytes)
+	default:
+		return NewFileAST(bytes)
+	}
+}
@@ -0,0 +1,47 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package encoding
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestNewConfigFileDispatchesByExtension(t *testing.T) {
+	cases := []struct {
+		path     string
+		bytes    []byte
+		wantType string
+	}{
+		{"Pulumi.dev.json", []byte(`{"a": "b"}`), "*encoding.jsonConfigFile"},
+		{"Pulumi.dev.toml", []byte("a = \"b\"\n"), "*encoding.tomlConfigFile"},
+		{"Pulumi.dev.hcl", []byte("a = \"b\"\n"), "*encoding.hclConfigFile"},
+		{"Pulumi.dev.yaml", []byte("a: b\n"), "*encoding.FileAST"},
+		{"Pulumi.dev.yml", []byte("a: b\n"), "*encoding.FileAST"},
+		{"Pulumi.dev.JSON", []byte(`{"a": "b"}`), "*encoding.jsonConfigFile"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.path, func(t *testing.T) {
+			cf, err := NewConfigFile(c.path, c.bytes)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := fmt.Sprintf("%T", cf); got != c.wantType {
+				t.Fatalf("got %s, want %s", got, c.wantType)
+			}
+		})
+	}
+}
@@ -0,0 +1,204 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package encoding
+
+import (
+	"github.com/pkg/errors"
+	"github.com/pulumi/pulumi/sdk/v2/go/common/resource/config"
+	"github.com/pulumi/pulumi/sdk/v2/go/common/util/contract"
+)
+
+// genNode is a treeNode backed by the plain map[string]interface{}/[]interface{}/scalar shape
+// that both encoding/json and the TOML library decode into. It has no notion of comments or
+// column positions, so backends built on it can't preserve formatting the way the YAML AST does.
+type genNode struct {
+	v interface{}
+}
+
+func (n *genNode) IsMap() bool {
+	_, ok := n.v.(map[string]interface{})
+	return ok
+}
+
+func (n *genNode) IsSeq() bool {
+	_, ok := n.v.([]interface{})
+	return ok
+}
+
+func (n *genNode) IsScalar() bool {
+	return !n.IsMap() && !n.IsSeq()
+}
+
+func (n *genNode) IsSecure() bool {
+	m, ok := n.v.(map[string]interface{})
+	if !ok || len(m) != 1 {
+		return false
+	}
+	_, ok = m["secure"]
+	return ok
+}
+
+func (n *genNode) Len() int {
+	if s, ok := n.v.([]interface{}); ok {
+		return len(s)
+	}
+	return 0
+}
+
+func (n *genNode) Get(key interface{}) (treeNode, error) {
+	switch k := key.(type) {
+	case string:
+		m, ok := n.v.(map[string]interface{})
+		if !ok {
+			return nil, errors.Errorf("expected a map, got %T", n.v)
+		}
+		child, ok := m[k]
+		if !ok {
+			return nil, nil
+		}
+		return &genNode{v: child}, nil
+	case int:
+		s, ok := n.v.([]interface{})
+		if !ok {
+			return nil, errors.Errorf("expected an array, got %T", n.v)
+		}
+		if k < 0 || k >= len(s) {
+			return nil, nil
+		}
+		return &genNode{v: s[k]}, nil
+	default:
+		contract.Failf("unexpected key type %T", key)
+		return nil, nil
+	}
+}
+
+func (n *genNode) Set(key interface{}, value treeNode) error {
+	gv := value.(*genNode).v
+	switch k := key.(type) {
+	case string:
+		m, ok := n.v.(map[string]interface{})
+		if !ok {
+			if n.v != nil {
+				return errors.Errorf("expected a map, got %T", n.v)
+			}
+			m = map[string]interface{}{}
+		}
+		m[k] = gv
+		n.v = m
+	case int:
+		s, _ := n.v.([]interface{})
+		switch {
+		case k == len(s):
+			s = append(s, gv)
+		case k >= 0 && k < len(s):
+			s[k] = gv
+		default:
+			return errors.New("array index out of range")
+		}
+		n.v = s
+	default:
+		contract.Failf("unexpected key type %T", key)
+	}
+	return nil
+}
+
+func (n *genNode) Remove(key interface{}) {
+	switch k := key.(type) {
+	case string:
+		if m, ok := n.v.(map[string]interface{}); ok {
+			delete(m, k)
+		}
+	case int:
+		if s, ok := n.v.([]interface{}); ok && k >= 0 && k < len(s) {
+			n.v = append(s[:k], s[k+1:]...)
+		}
+	}
+}
+
+// genNodeFactory builds genNodes, used as the nodeFactory for every backend built on genNode.
+type genNodeFactory struct{}
+
+func (genNodeFactory) newMap() treeNode { return &genNode{v: map[string]interface{}{}} }
+func (genNodeFactory) newSeq() treeNode { return &genNode{v: []interface{}{}} }
+func (genNodeFactory) newScalar(value config.Value) treeNode {
+	if value.Secure() {
+		return &genNode{v: map[string]interface{}{"secure": value.RawValue()}}
+	}
+	return &genNode{v: config.AdjustObjectValue(value, true)}
+}
+
+// genNodeRoot walks to the rootKey namespace within root (a *genNode wrapping a
+// map[string]interface{}), creating it as an empty map if it doesn't already exist.
+func genNodeRoot(root *genNode, rootKey string) (*genNode, error) {
+	if len(rootKey) == 0 {
+		return root, nil
+	}
+
+	child, err := root.Get(rootKey)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to walk to rootKey: %q", rootKey)
+	}
+	if child == nil {
+		child = &genNode{v: map[string]interface{}{}}
+		if err := root.Set(rootKey, child); err != nil {
+			return nil, err
+		}
+	}
+	gn, ok := child.(*genNode)
+	if !ok || !gn.IsMap() {
+		return nil, errors.Errorf("config key %q is not a map", rootKey)
+	}
+	return gn, nil
+}
+
+// genNodeLookupRoot walks to the rootKey namespace within root (a *genNode wrapping a
+// map[string]interface{}), returning (nil, nil) if it doesn't exist instead of creating it. Unlike
+// genNodeRoot, this never mutates root, so it's the right helper for read-only and removal paths
+// where a missing rootKey means "nothing to do" rather than "create it".
+func genNodeLookupRoot(root *genNode, rootKey string) (*genNode, error) {
+	if len(rootKey) == 0 {
+		return root, nil
+	}
+
+	child, err := root.Get(rootKey)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to walk to rootKey: %q", rootKey)
+	}
+	if child == nil {
+		return nil, nil
+	}
+	gn, ok := child.(*genNode)
+	if !ok || !gn.IsMap() {
+		return nil, errors.Errorf("config key %q is not a map", rootKey)
+	}
+	return gn, nil
+}
+
+// configPath builds the full property path ([]interface{} of string/int segments) that
+// SetConfig/RemoveConfig operate on: the top-level key, followed by the nested path segments
+// when the key's name portion is itself a path.
+func configPath(key config.Key, path bool) ([]interface{}, error) {
+	if !path {
+		return []interface{}{key.String()}, nil
+	}
+	pathSegments, configKey, err := config.ParseKeyPath(key)
+	if err != nil {
+		return nil, err
+	}
+	p := make([]interface{}, 0, len(pathSegments))
+	p = append(p, configKey.String())
+	p = append(p, pathSegments[1:]...)
+	return p, nil
+}
@@ -0,0 +1,207 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package encoding
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/pkg/errors"
+	"github.com/pulumi/pulumi/sdk/v2/go/common/resource/config"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// hclConfigFile is the HCL ConfigFile backend. It only supports the subset of HCL needed to
+// express stack config -- top-level attributes and nested blocks with literal string, number,
+// bool, and list values -- since expressions that reference variables or call functions have no
+// meaning outside of a full Pulumi program evaluation.
+type hclConfigFile struct {
+	root map[string]interface{}
+}
+
+// newHCLConfigFile parses bytes as an HCL stack config document.
+func newHCLConfigFile(bytes []byte) (*hclConfigFile, error) {
+	if len(bytes) == 0 {
+		return &hclConfigFile{}, nil
+	}
+
+	file, diags := hclsyntax.ParseConfig(bytes, "Pulumi.hcl", hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		return nil, errors.Wrap(diags, "failed to parse HCL file")
+	}
+
+	root, err := hclBodyToMap(file.Body.(*hclsyntax.Body))
+	if err != nil {
+		return nil, err
+	}
+	return &hclConfigFile{root: root}, nil
+}
+
+// hclBodyToMap evaluates every attribute in body as a literal and recurses into nested blocks,
+// producing the same map[string]interface{} shape as the JSON and TOML backends.
+func hclBodyToMap(body *hclsyntax.Body) (map[string]interface{}, error) {
+	result := map[string]interface{}{}
+
+	for name, attr := range body.Attributes {
+		v, diags := attr.Expr.Value(nil)
+		if diags.HasErrors() {
+			return nil, errors.Wrapf(diags, "failed to evaluate %q", name)
+		}
+		goValue, err := ctyToGo(v)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to evaluate %q", name)
+		}
+		result[name] = goValue
+	}
+
+	for _, block := range body.Blocks {
+		child, err := hclBodyToMap(block.Body)
+		if err != nil {
+			return nil, err
+		}
+		result[block.Type] = child
+	}
+
+	return result, nil
+}
+
+// ctyToGo converts a literal cty.Value -- string, number, bool, or a list/tuple/set of any of
+// those -- to the corresponding Go value (string, float64, bool, or []interface{}).
+func ctyToGo(v cty.Value) (interface{}, error) {
+	t := v.Type()
+	switch {
+	case t == cty.String:
+		return v.AsString(), nil
+	case t == cty.Bool:
+		return v.True(), nil
+	case t == cty.Number:
+		f, _ := v.AsBigFloat().Float64()
+		return f, nil
+	case t.IsListType() || t.IsTupleType() || t.IsSetType():
+		result := []interface{}{}
+		for it := v.ElementIterator(); it.Next(); {
+			_, ev := it.Element()
+			goValue, err := ctyToGo(ev)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, goValue)
+		}
+		return result, nil
+	default:
+		return nil, errors.Errorf("unsupported value of type %s", t.FriendlyName())
+	}
+}
+
+func (f *hclConfigFile) IsEmpty() bool {
+	return len(f.root) == 0
+}
+
+func (f *hclConfigFile) HasKey(k string) bool {
+	_, ok := f.root[k]
+	return ok
+}
+
+func (f *hclConfigFile) Marshal() []byte {
+	var b strings.Builder
+	writeHCLBody(&b, f.root, 0)
+	return []byte(b.String())
+}
+
+// writeHCLBody writes m as a sequence of "key = value" attributes and "key { ... }" blocks,
+// indenting nested blocks by two spaces per level.
+func writeHCLBody(b *strings.Builder, m map[string]interface{}, indent int) {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	prefix := strings.Repeat("  ", indent)
+	for _, k := range keys {
+		switch v := m[k].(type) {
+		case map[string]interface{}:
+			fmt.Fprintf(b, "%s%s {\n", prefix, k)
+			writeHCLBody(b, v, indent+1)
+			fmt.Fprintf(b, "%s}\n", prefix)
+		default:
+			fmt.Fprintf(b, "%s%s = %s\n", prefix, k, hclLiteral(v))
+		}
+	}
+}
+
+// hclLiteral renders a single scalar or list Go value as HCL source.
+func hclLiteral(v interface{}) string {
+	switch t := v.(type) {
+	case string:
+		return strconv.Quote(t)
+	case bool:
+		return strconv.FormatBool(t)
+	case float64:
+		return strconv.FormatFloat(t, 'g', -1, 64)
+	case []interface{}:
+		return hclList(t)
+	default:
+		return strconv.Quote(fmt.Sprintf("%v", t))
+	}
+}
+
+// hclList renders a Go slice as an HCL list literal, e.g. `["a", "b"]`.
+func hclList(items []interface{}) string {
+	parts := make([]string, len(items))
+	for i, item := range items {
+		parts[i] = hclLiteral(item)
+	}
+	return "[" + strings.Join(parts, ", ") + "]"
+}
+
+func (f *hclConfigFile) SetConfig(rootKey string, key config.Key, value config.Value, path bool) error {
+	if f.root == nil {
+		f.root = map[string]interface{}{}
+	}
+	target, err := genNodeRoot(&genNode{v: f.root}, rootKey)
+	if err != nil {
+		return err
+	}
+
+	p, err := configPath(key, path)
+	if err != nil {
+		return err
+	}
+	return setConfigPath(target, genNodeFactory{}, p, value)
+}
+
+func (f *hclConfigFile) RemoveConfig(rootKey string, k config.Key, path bool) error {
+	if f.root == nil {
+		return nil
+	}
+	target, err := genNodeLookupRoot(&genNode{v: f.root}, rootKey)
+	if err != nil {
+		return err
+	}
+	if target == nil {
+		return nil
+	}
+
+	p, err := configPath(k, path)
+	if err != nil {
+		return err
+	}
+	return removeConfigPath(target, p)
+}
@@ -0,0 +1,78 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package encoding
+
+import (
+	"testing"
+
+	"github.com/pulumi/pulumi/sdk/v2/go/common/resource/config"
+)
+
+func TestHCLConfigFileParsesNestedBlocksAndLists(t *testing.T) {
+	f, err := newHCLConfigFile([]byte(`
+config {
+  servers = ["a", "b"]
+}
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	configBlock, ok := f.root["config"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("got %+v, want a config block", f.root)
+	}
+	servers, ok := configBlock["servers"].([]interface{})
+	if !ok || len(servers) != 2 || servers[0] != "a" || servers[1] != "b" {
+		t.Fatalf("got servers %+v, want [a b]", configBlock["servers"])
+	}
+}
+
+func TestHCLConfigFileSetConfigRoundTrips(t *testing.T) {
+	f, err := newHCLConfigFile(nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	key := config.MustMakeKey("proj", "servers")
+	if err := f.SetConfig("config", key, config.NewValue("a"), false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	roundTripped, err := newHCLConfigFile(f.Marshal())
+	if err != nil {
+		t.Fatalf("failed to re-parse marshaled output: %v\n%s", err, f.Marshal())
+	}
+	if !roundTripped.HasKey("config") {
+		t.Fatalf("got %+v, want a config block to survive a round trip", roundTripped.root)
+	}
+}
+
+// TestHCLConfigFileRemoveConfigMissingRootKeyIsNoOp mirrors the JSON/TOML backends' regression
+// test: RemoveConfig must not auto-vivify an absent rootKey block.
+func TestHCLConfigFileRemoveConfigMissingRootKeyIsNoOp(t *testing.T) {
+	f, err := newHCLConfigFile([]byte(`foo = "bar"` + "\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	key := config.MustMakeKey("proj", "baz")
+	if err := f.RemoveConfig("config", key, false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if f.HasKey("config") {
+		t.Fatalf("RemoveConfig must not create rootKey %q when it was never present", "config")
+	}
+}
@@ -0,0 +1,171 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package encoding
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/pulumi/go-yaml/ast"
+	"github.com/pulumi/pulumi/sdk/v2/go/common/resource"
+)
+
+// InterpolationError is returned by Resolve when a "${...}" reference in a config value could
+// not be substituted, either because the expression was malformed or because a required
+// variable (via the ":?" operator) was unset.
+type InterpolationError struct {
+	// Path identifies the config value that contains the offending expression.
+	Path resource.PropertyPath
+	// Expr is the raw "${...}" expression that could not be resolved.
+	Expr string
+	// Reason explains why resolution failed.
+	Reason string
+}
+
+func (e *InterpolationError) Error() string {
+	return fmt.Sprintf("%v: failed to resolve %q: %s", e.Path, e.Expr, e.Reason)
+}
+
+// envLookup mirrors the behavior of os.LookupEnv but lets Resolve prefer a caller-provided map
+// of overrides before falling back to the process environment.
+type envLookup func(name string) (string, bool)
+
+// Resolve walks the AST substituting shell-style variable references found in string values --
+// "${VAR}", "${VAR:-default}", "${VAR:?err}", and "${VAR:+alt}" -- with entries from env,
+// falling back to the process environment for any name env does not contain. A literal "$" is
+// written as "$$". This mirrors the interpolation semantics docker's compose-go loader applies
+// to compose files.
+//
+// Resolve mutates the AST in place, so a subsequent Marshal reflects the resolved values.
+func (f *FileAST) Resolve(env map[string]string) error {
+	if f.IsEmpty() {
+		return nil
+	}
+
+	lookup := func(name string) (string, bool) {
+		if v, ok := env[name]; ok {
+			return v, true
+		}
+		return os.LookupEnv(name)
+	}
+
+	return resolveNode(f.ast.Docs[0].Body, nil, lookup)
+}
+
+// resolveNode recursively applies interpolate to every string leaf reachable from n, including
+// the plaintext half of a `{secure: "..."}` value, tracking the property path as it descends so
+// that an InterpolationError can point at the exact config value.
+func resolveNode(n ast.Node, path resource.PropertyPath, lookup envLookup) error {
+	switch t := n.(type) {
+	case *ast.MappingNode:
+		for _, v := range t.Values {
+			if err := resolveNode(v.Value, append(path, v.Key.String()), lookup); err != nil {
+				return err
+			}
+		}
+	case *ast.MappingValueNode:
+		return resolveNode(t.Value, path, lookup)
+	case *ast.SequenceNode:
+		for i, v := range t.Values {
+			if err := resolveNode(v, append(path, i), lookup); err != nil {
+				return err
+			}
+		}
+	case *ast.StringNode:
+		resolved, err := interpolate(t.Value, path, lookup)
+		if err != nil {
+			return err
+		}
+		t.Value = resolved
+		t.Token.Value = resolved
+	}
+	return nil
+}
+
+// interpolate expands every "${...}" reference in s, copying literal text through unchanged and
+// unescaping "$$" to a literal "$".
+func interpolate(s string, path resource.PropertyPath, lookup envLookup) (string, error) {
+	var out strings.Builder
+	for i := 0; i < len(s); {
+		switch {
+		case s[i] != '$':
+			out.WriteByte(s[i])
+			i++
+		case i+1 < len(s) && s[i+1] == '$':
+			out.WriteByte('$')
+			i += 2
+		case i+1 < len(s) && s[i+1] == '{':
+			end := strings.IndexByte(s[i+2:], '}')
+			if end < 0 {
+				return "", &InterpolationError{Path: path, Expr: s[i:], Reason: "unterminated variable reference"}
+			}
+			end += i + 2
+			value, err := resolveExpr(s[i+2:end], path, lookup)
+			if err != nil {
+				return "", err
+			}
+			out.WriteString(value)
+			i = end + 1
+		default:
+			out.WriteByte(s[i])
+			i++
+		}
+	}
+	return out.String(), nil
+}
+
+// resolveExpr resolves the contents of a single "${...}" reference: a bare variable name, or a
+// name combined with one of the ":-" (default), ":?" (require), or ":+" (alternate) operators.
+func resolveExpr(expr string, path resource.PropertyPath, lookup envLookup) (string, error) {
+	name, op, arg := expr, "", ""
+	opIdx := -1
+	for _, candidate := range []string{":-", ":?", ":+"} {
+		// The operator is whichever of ":-", ":?", ":+" appears first in expr, not whichever is
+		// checked first: an arg like "need value:-x" after a ":?" earlier in the same expression
+		// must not be mistaken for a second, higher-priority operator.
+		if idx := strings.Index(expr, candidate); idx >= 0 && (opIdx < 0 || idx < opIdx) {
+			opIdx, name, op, arg = idx, expr[:idx], candidate, expr[idx+2:]
+		}
+	}
+
+	value, ok := lookup(name)
+	switch op {
+	case ":-":
+		if !ok || value == "" {
+			return arg, nil
+		}
+		return value, nil
+	case ":?":
+		if !ok || value == "" {
+			reason := arg
+			if reason == "" {
+				reason = fmt.Sprintf("%q is not set", name)
+			}
+			return "", &InterpolationError{Path: path, Expr: "${" + expr + "}", Reason: reason}
+		}
+		return value, nil
+	case ":+":
+		if ok && value != "" {
+			return arg, nil
+		}
+		return "", nil
+	default:
+		if !ok {
+			return "", nil
+		}
+		return value, nil
+	}
+}
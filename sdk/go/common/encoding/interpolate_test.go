@@ -0,0 +1,118 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package encoding
+
+import "testing"
+
+func lookupFrom(env map[string]string) envLookup {
+	return func(name string) (string, bool) {
+		v, ok := env[name]
+		return v, ok
+	}
+}
+
+func TestInterpolate(t *testing.T) {
+	cases := []struct {
+		name    string
+		input   string
+		env     map[string]string
+		want    string
+		wantErr bool
+	}{
+		{name: "no references", input: "plain text", want: "plain text"},
+		{name: "bare reference", input: "${FOO}", env: map[string]string{"FOO": "bar"}, want: "bar"},
+		{name: "unset bare reference", input: "${FOO}", want: ""},
+		{name: "escaped dollar", input: "$${FOO}", env: map[string]string{"FOO": "bar"}, want: "${FOO}"},
+		{
+			name: "default used when unset", input: "${FOO:-fallback}", want: "fallback",
+		},
+		{
+			name: "default ignored when set", input: "${FOO:-fallback}",
+			env: map[string]string{"FOO": "bar"}, want: "bar",
+		},
+		{
+			name: "default used when empty", input: "${FOO:-fallback}",
+			env: map[string]string{"FOO": ""}, want: "fallback",
+		},
+		{
+			name: "alternate used when set", input: "${FOO:+alt}",
+			env: map[string]string{"FOO": "bar"}, want: "alt",
+		},
+		{name: "alternate ignored when unset", input: "${FOO:+alt}", want: ""},
+		{
+			name: "required present", input: "${FOO:?missing}",
+			env: map[string]string{"FOO": "bar"}, want: "bar",
+		},
+		{name: "required missing errors", input: "${FOO:?missing}", wantErr: true},
+		{name: "unterminated reference errors", input: "${FOO", wantErr: true},
+		{
+			name: "mixed literal and reference", input: "postgres://${HOST:-localhost}/db",
+			want: "postgres://localhost/db",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := interpolate(c.input, nil, lookupFrom(c.env))
+			if c.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got result %q", got)
+				}
+				if _, ok := err.(*InterpolationError); !ok {
+					t.Fatalf("expected an *InterpolationError, got %T", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != c.want {
+				t.Fatalf("got %q, want %q", got, c.want)
+			}
+		})
+	}
+}
+
+func TestResolveExprRequiredCustomMessage(t *testing.T) {
+	_, err := resolveExpr("FOO:?FOO must be set", nil, lookupFrom(nil))
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	interpErr, ok := err.(*InterpolationError)
+	if !ok {
+		t.Fatalf("expected an *InterpolationError, got %T", err)
+	}
+	if interpErr.Reason != "FOO must be set" {
+		t.Fatalf("got reason %q, want %q", interpErr.Reason, "FOO must be set")
+	}
+}
+
+// TestResolveExprUsesLeftmostOperator is a regression test: resolveExpr used to check ":-", ":?",
+// and ":+" in that fixed priority order rather than taking whichever occurs first in the
+// expression, so an unrelated ":-"-like substring inside a later ":?" error message was mistaken
+// for the higher-priority operator.
+func TestResolveExprUsesLeftmostOperator(t *testing.T) {
+	_, err := resolveExpr("FOO:?need value:-x", nil, lookupFrom(nil))
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	interpErr, ok := err.(*InterpolationError)
+	if !ok {
+		t.Fatalf("expected an *InterpolationError, got %T", err)
+	}
+	if interpErr.Reason != "need value:-x" {
+		t.Fatalf("got reason %q, want %q", interpErr.Reason, "need value:-x")
+	}
+}
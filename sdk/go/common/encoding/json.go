@@ -0,0 +1,95 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package encoding
+
+import (
+	"encoding/json"
+
+	"github.com/pkg/errors"
+	"github.com/pulumi/pulumi/sdk/v2/go/common/resource/config"
+)
+
+// jsonConfigFile is the JSON ConfigFile backend. It stores its document as a plain
+// map[string]interface{} decoded by encoding/json, so (unlike the YAML AST) it cannot preserve
+// comments or key ordering across an edit.
+type jsonConfigFile struct {
+	root map[string]interface{}
+}
+
+// newJSONConfigFile parses bytes as a JSON stack config document.
+func newJSONConfigFile(bytes []byte) (*jsonConfigFile, error) {
+	if len(bytes) == 0 {
+		return &jsonConfigFile{}, nil
+	}
+	var root map[string]interface{}
+	if err := json.Unmarshal(bytes, &root); err != nil {
+		return nil, errors.Wrap(err, "failed to parse JSON file")
+	}
+	return &jsonConfigFile{root: root}, nil
+}
+
+func (f *jsonConfigFile) IsEmpty() bool {
+	return len(f.root) == 0
+}
+
+func (f *jsonConfigFile) HasKey(k string) bool {
+	_, ok := f.root[k]
+	return ok
+}
+
+func (f *jsonConfigFile) Marshal() []byte {
+	bytes, err := json.MarshalIndent(f.root, "", "  ")
+	if err != nil {
+		// f.root only ever holds values produced by encoding/json and our own scalar types, so
+		// it is always re-marshalable.
+		panic(err)
+	}
+	return bytes
+}
+
+func (f *jsonConfigFile) SetConfig(rootKey string, key config.Key, value config.Value, path bool) error {
+	if f.root == nil {
+		f.root = map[string]interface{}{}
+	}
+	target, err := genNodeRoot(&genNode{v: f.root}, rootKey)
+	if err != nil {
+		return err
+	}
+
+	p, err := configPath(key, path)
+	if err != nil {
+		return err
+	}
+	return setConfigPath(target, genNodeFactory{}, p, value)
+}
+
+func (f *jsonConfigFile) RemoveConfig(rootKey string, k config.Key, path bool) error {
+	if f.root == nil {
+		return nil
+	}
+	target, err := genNodeLookupRoot(&genNode{v: f.root}, rootKey)
+	if err != nil {
+		return err
+	}
+	if target == nil {
+		return nil
+	}
+
+	p, err := configPath(k, path)
+	if err != nil {
+		return err
+	}
+	return removeConfigPath(target, p)
+}
@@ -0,0 +1,80 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package encoding
+
+import (
+	"testing"
+
+	"github.com/pulumi/pulumi/sdk/v2/go/common/resource/config"
+)
+
+func TestJSONConfigFileSetConfigRoundTrips(t *testing.T) {
+	f, err := newJSONConfigFile([]byte(`{"proj:foo": "bar"}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	key := config.MustMakeKey("proj", "baz")
+	if err := f.SetConfig("config", key, config.NewValue("qux"), false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	roundTripped, err := newJSONConfigFile(f.Marshal())
+	if err != nil {
+		t.Fatalf("failed to re-parse marshaled output: %v", err)
+	}
+	if !roundTripped.HasKey("proj:foo") || !roundTripped.HasKey("config") {
+		t.Fatalf("got %+v, want both the original and the newly set key to survive a round trip",
+			roundTripped.root)
+	}
+}
+
+// TestJSONConfigFileRemoveConfigMissingRootKeyIsNoOp is a regression test: RemoveConfig used to
+// reuse the same auto-vivifying helper as SetConfig, so removing a key under a rootKey that wasn't
+// present yet silently created an empty map there instead of leaving the document untouched.
+func TestJSONConfigFileRemoveConfigMissingRootKeyIsNoOp(t *testing.T) {
+	f, err := newJSONConfigFile([]byte(`{"proj:foo": "bar"}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	key := config.MustMakeKey("proj", "baz")
+	if err := f.RemoveConfig("config", key, false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if f.HasKey("config") {
+		t.Fatalf("RemoveConfig must not create rootKey %q when it was never present", "config")
+	}
+}
+
+func TestJSONConfigFileRemoveConfigRemovesExistingKey(t *testing.T) {
+	f, err := newJSONConfigFile([]byte(`{"config": {"proj:foo": "bar"}}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	key := config.MustMakeKey("proj", "foo")
+	if err := f.RemoveConfig("config", key, false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	configRoot, ok := f.root["config"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("got %+v, want config root to still be a map", f.root)
+	}
+	if _, ok := configRoot["proj:foo"]; ok {
+		t.Fatalf("got %+v, want proj:foo removed", configRoot)
+	}
+}
@@ -0,0 +1,232 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package encoding
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/pulumi/go-yaml/ast"
+	"github.com/pulumi/go-yaml/token"
+	"github.com/pulumi/pulumi/sdk/v2/go/common/resource"
+)
+
+// sequenceTagAppend and sequenceTagPrepend are the opt-in YAML tags a sequence value can carry
+// to ask MergeFileASTs to concatenate it with the corresponding base sequence instead of
+// replacing it outright.
+const (
+	sequenceTagAppend  = "!append"
+	sequenceTagPrepend = "!prepend"
+)
+
+// MergeError is returned by MergeFileASTs when an overlay disagrees with the base document on
+// the shape of a value at the same path, e.g. one side is a scalar and the other a map.
+type MergeError struct {
+	// Path identifies the config value whose shape conflicted between base and overlay.
+	Path resource.PropertyPath
+	// Reason explains the nature of the conflict.
+	Reason string
+}
+
+func (e *MergeError) Error() string {
+	return fmt.Sprintf("%v: %s", e.Path, e.Reason)
+}
+
+// MergeFileASTs deep-merges overlays onto base and returns the resulting AST, so that e.g.
+// `Pulumi.yaml` + `Pulumi.dev.yaml` + `Pulumi.local.yaml` resolve to a single document, similar
+// to what compose-go does for stacked compose files. Mapping nodes are merged key-by-key,
+// sequence nodes are replaced unless an overlay value carries the "!append" or "!prepend" tag,
+// and a secure value in any overlay always wins over a plaintext value in the base. Comments and
+// column positions from base are preserved everywhere a key is not overridden, so the result
+// still round-trips through Marshal.
+//
+// base and overlays are left untouched: the merge runs against fresh clones of each, so callers
+// that hold onto the arguments they passed in (e.g. to merge the same base against several
+// different sets of overlays) can keep doing so safely.
+func MergeFileASTs(base *FileAST, overlays ...*FileAST) (*FileAST, error) {
+	if base == nil {
+		return nil, errors.New("base must not be nil")
+	}
+
+	result, err := cloneFileAST(base)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, overlay := range overlays {
+		if overlay == nil || overlay.IsEmpty() {
+			continue
+		}
+		if result.IsEmpty() {
+			result, err = cloneFileAST(overlay)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		overlayClone, err := cloneFileAST(overlay)
+		if err != nil {
+			return nil, err
+		}
+
+		baseRoot := result.ast.Docs[0].Body.(*ast.MappingNode)
+		overlayRoot := overlayClone.ast.Docs[0].Body.(*ast.MappingNode)
+		if err := mergeMappingNodes(baseRoot, overlayRoot, nil); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// cloneFileAST returns a deep copy of f by round-tripping it through Marshal/NewFileAST, so that
+// mergeMappingNodes -- which mutates its base argument's nodes in place -- never touches the
+// caller's original AST.
+func cloneFileAST(f *FileAST) (*FileAST, error) {
+	clone, err := NewFileAST(f.Marshal())
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to clone file AST")
+	}
+	return clone, nil
+}
+
+// mergeMappingNodes merges every entry of overlay into base in place, recursing into shared keys
+// and appending keys that are new to base.
+func mergeMappingNodes(base, overlay *ast.MappingNode, path resource.PropertyPath) error {
+	for _, ov := range overlay.Values {
+		key := ov.Key.String()
+		childPath := append(append(resource.PropertyPath{}, path...), key)
+
+		existing := findValue(base, key)
+		if existing == nil {
+			base.Values = append(base.Values, ov)
+			continue
+		}
+
+		merged, err := mergeNodes(existing.Value, ov.Value, childPath)
+		if err != nil {
+			return err
+		}
+		existing.Value = merged
+	}
+	return nil
+}
+
+// mergeNodes merges overlayNode over baseNode and returns the node that should replace baseNode
+// in the result, following the rules documented on MergeFileASTs.
+func mergeNodes(baseNode, overlayNode ast.Node, path resource.PropertyPath) (ast.Node, error) {
+	// A secure value introduced by an overlay always takes precedence, regardless of what shape
+	// the base value has.
+	if isSecureValue(overlayNode) {
+		return overlayNode, nil
+	}
+
+	// An "!append" or "!prepend" tag parses as a TagNode wrapping the overlay sequence; unwrap it
+	// so the sequence case below sees the sequence itself.
+	tag := sequenceMergeTag(overlayNode)
+	if tag != "" {
+		overlayNode = overlayNode.(*ast.TagNode).Value
+	}
+
+	switch o := overlayNode.(type) {
+	case *ast.MappingNode:
+		switch b := baseNode.(type) {
+		case *ast.MappingNode:
+			if err := mergeMappingNodes(b, o, path); err != nil {
+				return nil, err
+			}
+			return b, nil
+		default:
+			if isSecureValue(baseNode) {
+				return overlayNode, nil
+			}
+			return nil, &MergeError{
+				Path:   path,
+				Reason: fmt.Sprintf("cannot merge a map over %s", describeNode(baseNode)),
+			}
+		}
+
+	case *ast.SequenceNode:
+		switch b := baseNode.(type) {
+		case *ast.SequenceNode:
+			switch tag {
+			case sequenceTagAppend:
+				b.Values = append(b.Values, o.Values...)
+				return b, nil
+			case sequenceTagPrepend:
+				b.Values = append(append([]ast.Node{}, o.Values...), b.Values...)
+				return b, nil
+			default:
+				return overlayNode, nil
+			}
+		default:
+			return nil, &MergeError{
+				Path:   path,
+				Reason: fmt.Sprintf("cannot merge a sequence over %s", describeNode(baseNode)),
+			}
+		}
+
+	default:
+		if _, ok := baseNode.(*ast.MappingNode); ok {
+			return nil, &MergeError{Path: path, Reason: "cannot merge a scalar value over a map"}
+		}
+		return overlayNode, nil
+	}
+}
+
+// describeNode gives a short, human-readable name for a node's shape, for use in MergeError
+// messages.
+func describeNode(n ast.Node) string {
+	switch n.(type) {
+	case *ast.MappingNode:
+		return "a map"
+	case *ast.SequenceNode:
+		return "a sequence"
+	default:
+		return "a scalar value"
+	}
+}
+
+// sequenceMergeTag inspects the YAML tag wrapping a sequence node, if any, to decide how it should
+// be combined with the corresponding base sequence during a merge.
+func sequenceMergeTag(n ast.Node) string {
+	t, ok := n.(*ast.TagNode)
+	if !ok {
+		return ""
+	}
+	if _, isSeq := t.Value.(*ast.SequenceNode); !isSeq {
+		return ""
+	}
+	tok := t.GetToken()
+	if tok == nil || tok.Type != token.TagType {
+		return ""
+	}
+	switch tok.Value {
+	case sequenceTagAppend, sequenceTagPrepend:
+		return tok.Value
+	default:
+		return ""
+	}
+}
+
+// findValue returns the MappingValueNode for key within node, or nil if node has no such key.
+func findValue(node *ast.MappingNode, key string) *ast.MappingValueNode {
+	for _, v := range node.Values {
+		if v.Key.String() == key {
+			return v
+		}
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package encoding
+
+import (
+	"testing"
+
+	"github.com/pulumi/go-yaml/ast"
+)
+
+func TestMergeNodesShapeConflicts(t *testing.T) {
+	newScalar := func() ast.Node { return newValueNode("hi", false, 0) }
+	newMapping := func() ast.Node { return newMappingNode("m", 0) }
+	newSequence := func() ast.Node { return newSequenceNode(0) }
+
+	cases := []struct {
+		name       string
+		base       func() ast.Node
+		overlay    func() ast.Node
+		wantErr    bool
+		wantReason string
+	}{
+		{name: "map over map merges", base: newMapping, overlay: newMapping},
+		{name: "sequence over sequence replaces", base: newSequence, overlay: newSequence},
+		{
+			name: "map over sequence conflicts", base: newSequence, overlay: newMapping,
+			wantErr: true, wantReason: "cannot merge a map over a sequence",
+		},
+		{
+			name: "sequence over map conflicts", base: newMapping, overlay: newSequence,
+			wantErr: true, wantReason: "cannot merge a sequence over a map",
+		},
+		{
+			name: "map over scalar conflicts", base: newScalar, overlay: newMapping,
+			wantErr: true, wantReason: "cannot merge a map over a scalar value",
+		},
+		{
+			name: "scalar over map conflicts", base: newMapping, overlay: newScalar,
+			wantErr: true, wantReason: "cannot merge a scalar value over a map",
+		},
+		{name: "scalar over sequence replaces", base: newSequence, overlay: newScalar},
+		{name: "scalar over scalar replaces", base: newScalar, overlay: newScalar},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := mergeNodes(c.base(), c.overlay(), nil)
+			if c.wantErr {
+				mergeErr, ok := err.(*MergeError)
+				if !ok {
+					t.Fatalf("expected a *MergeError, got %v (%T)", err, err)
+				}
+				if mergeErr.Reason != c.wantReason {
+					t.Fatalf("got reason %q, want %q", mergeErr.Reason, c.wantReason)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestMergeFileASTs(t *testing.T) {
+	base, err := NewFileAST([]byte("a: 1\nb: 2\nservers:\n  - one\nsecret: plain\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	overlay, err := NewFileAST([]byte(
+		"b: 3\nc: 4\nservers: !append\n  - two\nsecret:\n  secure: shh\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	merged, err := MergeFileASTs(base, overlay)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	roundTripped, err := NewFileAST(merged.Marshal())
+	if err != nil {
+		t.Fatalf("failed to re-parse merged output: %v\n%s", err, merged.Marshal())
+	}
+	if !roundTripped.HasKey("a") || !roundTripped.HasKey("b") || !roundTripped.HasKey("c") {
+		t.Fatalf("got %s, want a, b, and c all present", roundTripped.Marshal())
+	}
+}
+
+// TestMergeFileASTsDoesNotMutateArguments is a regression test: MergeFileASTs used to merge
+// overlays directly into base's AST nodes, so a caller that merged the same base against several
+// overlays in turn would see each prior overlay's changes bleed into the next merge.
+func TestMergeFileASTsDoesNotMutateArguments(t *testing.T) {
+	base, err := NewFileAST([]byte("a: 1\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	overlay, err := NewFileAST([]byte("b: 2\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := MergeFileASTs(base, overlay); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if base.HasKey("b") {
+		t.Fatalf("got %s, want base left untouched by the merge", base.Marshal())
+	}
+	if overlay.HasKey("a") {
+		t.Fatalf("got %s, want overlay left untouched by the merge", overlay.Marshal())
+	}
+}
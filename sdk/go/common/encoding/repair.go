@@ -0,0 +1,91 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package encoding
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pulumi/go-yaml/parser"
+)
+
+// RepairNote records a single automatic fix-up NewFileAST made while trying to recover a
+// parseable AST from a malformed YAML document.
+type RepairNote struct {
+	// Line is the 1-based line number the repair was applied at.
+	Line int
+	// Description explains what was changed, e.g. "closed 1 dangling flow collection(s)".
+	Description string
+}
+
+// repairYAML attempts a best-effort fix-up of a malformed YAML document so that the rest of it
+// stays usable: it closes any dangling "{"/"[" flow collections, fills in a placeholder value
+// after a trailing ":", and -- if the document still doesn't parse -- truncates the final
+// incomplete line, returning it separately as tail so the caller can preserve it verbatim.
+func repairYAML(src []byte) (repaired []byte, notes []RepairNote, tail string) {
+	lines := strings.Split(string(src), "\n")
+	fixed := make([]string, len(lines))
+	copy(fixed, lines)
+
+	if last := len(fixed) - 1; last >= 0 {
+		trimmed := strings.TrimRight(fixed[last], " \t")
+		if strings.HasSuffix(trimmed, ":") {
+			fixed[last] = trimmed + " null"
+			notes = append(notes, RepairNote{
+				Line:        last + 1,
+				Description: `inserted placeholder value after trailing ":"`,
+			})
+		}
+	}
+
+	var openBraces, openBrackets int
+	for _, l := range fixed {
+		openBraces += strings.Count(l, "{") - strings.Count(l, "}")
+		openBrackets += strings.Count(l, "[") - strings.Count(l, "]")
+	}
+	// A malformed document can just as easily have more closing braces/brackets than opening
+	// ones, which drives either counter negative; strings.Repeat panics on a negative count, so
+	// each one must be clamped independently rather than gated by a single "||" check.
+	var closing string
+	if openBraces > 0 {
+		closing += strings.Repeat("}", openBraces)
+	}
+	if openBrackets > 0 {
+		closing += strings.Repeat("]", openBrackets)
+	}
+	if closing != "" {
+		fixed[len(fixed)-1] += closing
+		notes = append(notes, RepairNote{
+			Line:        len(fixed),
+			Description: fmt.Sprintf("closed %d dangling flow collection(s)", len(closing)),
+		})
+	}
+
+	candidate := strings.Join(fixed, "\n")
+	if _, err := parser.ParseBytes([]byte(candidate), parser.ParseComments); err == nil {
+		return []byte(candidate), notes, ""
+	}
+
+	// Closing collections and patching the trailing ":" wasn't enough: drop the final line of
+	// the original (unpatched) document entirely and preserve it as the unparsed tail. None of
+	// the fix-ups attempted above made it into this returned document, so they don't belong in
+	// notes -- only the truncation itself does.
+	last := len(lines) - 1
+	if last < 0 {
+		return src, nil, ""
+	}
+	notes = []RepairNote{{Line: last + 1, Description: "truncated incomplete final line"}}
+	return []byte(strings.Join(lines[:last], "\n")), notes, lines[last]
+}
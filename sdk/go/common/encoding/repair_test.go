@@ -0,0 +1,60 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package encoding
+
+import "testing"
+
+// TestRepairYAMLTruncationOmitsDiscardedNotes is a regression test: when the trailing-":" and
+// brace-closing fix-ups don't produce a parseable document, repairYAML falls back to truncating
+// the original (unpatched) last line. The notes it reports must describe only what actually made
+// it into the returned document -- not the discarded intermediate attempt.
+func TestRepairYAMLTruncationOmitsDiscardedNotes(t *testing.T) {
+	// The dangling "{" and "[" make the brace-closing fix-up fire and record a note, but closing
+	// them in the wrong nesting order ("{[bar}]") is still invalid YAML, so repairYAML must fall
+	// through to truncation.
+	src := []byte("a: 1\nfoo: {[bar")
+
+	repaired, notes, tail := repairYAML(src)
+
+	if string(repaired) != "a: 1" {
+		t.Fatalf("got repaired %q, want %q", repaired, "a: 1")
+	}
+	if tail != "foo: {[bar" {
+		t.Fatalf("got tail %q, want %q", tail, "foo: {[bar")
+	}
+	if len(notes) != 1 || notes[0].Description != "truncated incomplete final line" {
+		t.Fatalf("got notes %+v, want exactly one truncation note", notes)
+	}
+}
+
+// TestRepairYAMLDoesNotPanicOnExcessClosingDelimiters is a regression test: a document with more
+// closing braces/brackets than opening ones anywhere in it drives one of the running open-count
+// totals negative, and strings.Repeat panics on a negative count. repairYAML must clamp each
+// counter independently instead of gating both on a single combined check.
+func TestRepairYAMLDoesNotPanicOnExcessClosingDelimiters(t *testing.T) {
+	src := []byte("a: }\nb: [1")
+
+	repaired, notes, tail := repairYAML(src)
+
+	if string(repaired) != "a: }\nb: [1]" {
+		t.Fatalf("got repaired %q, want %q", repaired, "a: }\nb: [1]")
+	}
+	if tail != "" {
+		t.Fatalf("got tail %q, want no tail", tail)
+	}
+	if len(notes) != 1 || notes[0].Description != "closed 1 dangling flow collection(s)" {
+		t.Fatalf("got notes %+v, want a single note closing the dangling bracket", notes)
+	}
+}
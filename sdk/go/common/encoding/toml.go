@@ -0,0 +1,93 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package encoding
+
+import (
+	"github.com/pelletier/go-toml/v2"
+	"github.com/pkg/errors"
+	"github.com/pulumi/pulumi/sdk/v2/go/common/resource/config"
+)
+
+// tomlConfigFile is the TOML ConfigFile backend. Like jsonConfigFile, it holds its document as a
+// plain map[string]interface{} and so cannot preserve comments across an edit.
+type tomlConfigFile struct {
+	root map[string]interface{}
+}
+
+// newTOMLConfigFile parses bytes as a TOML stack config document.
+func newTOMLConfigFile(bytes []byte) (*tomlConfigFile, error) {
+	if len(bytes) == 0 {
+		return &tomlConfigFile{}, nil
+	}
+	var root map[string]interface{}
+	if err := toml.Unmarshal(bytes, &root); err != nil {
+		return nil, errors.Wrap(err, "failed to parse TOML file")
+	}
+	return &tomlConfigFile{root: root}, nil
+}
+
+func (f *tomlConfigFile) IsEmpty() bool {
+	return len(f.root) == 0
+}
+
+func (f *tomlConfigFile) HasKey(k string) bool {
+	_, ok := f.root[k]
+	return ok
+}
+
+func (f *tomlConfigFile) Marshal() []byte {
+	bytes, err := toml.Marshal(f.root)
+	if err != nil {
+		// f.root only ever holds values produced by toml.Unmarshal and our own scalar types, so
+		// it is always re-marshalable.
+		panic(err)
+	}
+	return bytes
+}
+
+func (f *tomlConfigFile) SetConfig(rootKey string, key config.Key, value config.Value, path bool) error {
+	if f.root == nil {
+		f.root = map[string]interface{}{}
+	}
+	target, err := genNodeRoot(&genNode{v: f.root}, rootKey)
+	if err != nil {
+		return err
+	}
+
+	p, err := configPath(key, path)
+	if err != nil {
+		return err
+	}
+	return setConfigPath(target, genNodeFactory{}, p, value)
+}
+
+func (f *tomlConfigFile) RemoveConfig(rootKey string, k config.Key, path bool) error {
+	if f.root == nil {
+		return nil
+	}
+	target, err := genNodeLookupRoot(&genNode{v: f.root}, rootKey)
+	if err != nil {
+		return err
+	}
+	if target == nil {
+		return nil
+	}
+
+	p, err := configPath(k, path)
+	if err != nil {
+		return err
+	}
+	return removeConfigPath(target, p)
+}
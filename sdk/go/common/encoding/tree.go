@@ -0,0 +1,147 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package encoding
+
+import (
+	"github.com/pkg/errors"
+	"github.com/pulumi/pulumi/sdk/v2/go/common/resource/config"
+	"github.com/pulumi/pulumi/sdk/v2/go/common/util/contract"
+)
+
+// treeNode is a minimal, format-agnostic view over a single node of a config document. Each
+// backend that isn't the original go-yaml AST (JSON, TOML, HCL) implements this interface over
+// its own in-memory representation so that the nested property-path traversal used by SetConfig
+// and RemoveConfig -- walking "a.b[0].c", creating intermediate maps/sequences, and detecting
+// shape conflicts along the way -- only has to be written once, in setConfigPath and
+// removeConfigPath below.
+//
+// The original YAML backend predates this interface and keeps its own hand-rolled traversal in
+// ast.go, since it additionally has to track column positions and comments for pretty-printing;
+// teasing that apart is left for a follow-up.
+type treeNode interface {
+	// Get returns the child at key (a string for a map, an int for a sequence), or nil if the
+	// child is absent.
+	Get(key interface{}) (treeNode, error)
+	// Set assigns value as the child at key, appending to a sequence when key == Len().
+	Set(key interface{}, value treeNode) error
+	// Remove deletes the child at key, if present.
+	Remove(key interface{})
+	// IsMap reports whether this node is a mapping.
+	IsMap() bool
+	// IsSeq reports whether this node is a sequence.
+	IsSeq() bool
+	// IsScalar reports whether this node is neither a mapping nor a sequence.
+	IsScalar() bool
+	// IsSecure reports whether this node is a secure-value wrapper, i.e. a map of the form
+	// {"secure": "..."}.
+	IsSecure() bool
+	// Len returns the number of elements in a sequence node; it is 0 for any other node.
+	Len() int
+}
+
+// nodeFactory constructs new treeNodes for a given backend, so that setConfigPath can create
+// intermediate containers without knowing their concrete type.
+type nodeFactory interface {
+	newMap() treeNode
+	newSeq() treeNode
+	newScalar(value config.Value) treeNode
+}
+
+// setConfigPath implements the nested path traversal shared by every generic-tree backend: it
+// walks root via the key path p, creating intermediate maps or sequences as needed (choosing
+// which based on whether the following path segment is a string or an int), and assigns value at
+// the final segment.
+//
+// Each level writes its (possibly mutated) child back into its own parent via Set once the
+// recursive call below it returns. This matters because a backend like genNode represents a
+// sequence as a Go slice: appending to it can reallocate the backing array, and a treeNode
+// returned by an earlier Get is otherwise left holding a now-orphaned copy of the old slice
+// header. Without the write-back, such a mutation would be silently lost.
+func setConfigPath(root treeNode, factory nodeFactory, p []interface{}, value config.Value) error {
+	contract.Assertf(len(p) > 0, "path must not be empty")
+	_, err := setConfigPathRec(root, factory, p, value)
+	return err
+}
+
+func setConfigPathRec(node treeNode, factory nodeFactory, p []interface{}, value config.Value) (treeNode, error) {
+	if len(p) == 1 {
+		if err := node.Set(p[0], factory.newScalar(value)); err != nil {
+			return nil, err
+		}
+		return node, nil
+	}
+
+	key := p[0]
+	child, err := node.Get(key)
+	if err != nil {
+		return nil, err
+	}
+
+	_, nextIsIndex := p[1].(int)
+	if child == nil || child.IsScalar() || child.IsSecure() {
+		if nextIsIndex {
+			child = factory.newSeq()
+		} else {
+			child = factory.newMap()
+		}
+	} else if nextIsIndex != child.IsSeq() {
+		if nextIsIndex {
+			return nil, errors.Errorf("an array was expected for index %v", p[1])
+		}
+		return nil, errors.Errorf("a map was expected for key %q", p[1])
+	}
+
+	child, err = setConfigPathRec(child, factory, p[1:], value)
+	if err != nil {
+		return nil, err
+	}
+	if err := node.Set(key, child); err != nil {
+		return nil, err
+	}
+	return node, nil
+}
+
+// removeConfigPath mirrors setConfigPath for deletion: it walks root to the second-to-last
+// segment of p and removes the final segment there, returning without error if any intermediate
+// segment is already absent. Like setConfigPath, every level writes its mutated child back into
+// its own parent so that a sequence element removal (which reslices the backing array) isn't
+// lost on the way back up.
+func removeConfigPath(root treeNode, p []interface{}) error {
+	contract.Assertf(len(p) > 0, "path must not be empty")
+	_, err := removeConfigPathRec(root, p)
+	return err
+}
+
+func removeConfigPathRec(node treeNode, p []interface{}) (treeNode, error) {
+	if len(p) == 1 {
+		node.Remove(p[0])
+		return node, nil
+	}
+
+	key := p[0]
+	child, err := node.Get(key)
+	if err != nil || child == nil {
+		return node, nil
+	}
+
+	child, err = removeConfigPathRec(child, p[1:])
+	if err != nil {
+		return nil, err
+	}
+	if err := node.Set(key, child); err != nil {
+		return nil, err
+	}
+	return node, nil
+}
@@ -0,0 +1,86 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package encoding
+
+import (
+	"testing"
+
+	"github.com/pulumi/pulumi/sdk/v2/go/common/resource/config"
+)
+
+// TestSetConfigPathAppendsToExistingSequence is a regression test: appending a value to an
+// existing sequence used to grow the backing slice on a detached copy of the node and never
+// write the new slice back into its parent, so the appended value silently vanished.
+func TestSetConfigPathAppendsToExistingSequence(t *testing.T) {
+	root := &genNode{v: map[string]interface{}{
+		"servers": []interface{}{"a", "b"},
+	}}
+
+	err := setConfigPath(root, genNodeFactory{}, []interface{}{"servers", 2}, config.NewValue("c"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	servers := root.v.(map[string]interface{})["servers"].([]interface{})
+	if len(servers) != 3 || servers[2] != "c" {
+		t.Fatalf("got servers %v, want [a b c]", servers)
+	}
+}
+
+// TestSetConfigPathCreatesNestedContainers exercises the common case of setting a deeply nested
+// key that doesn't exist yet, creating maps and sequences along the way.
+func TestSetConfigPathCreatesNestedContainers(t *testing.T) {
+	root := &genNode{v: map[string]interface{}{}}
+
+	err := setConfigPath(root, genNodeFactory{}, []interface{}{"a", "b", 0}, config.NewValue("v"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	a := root.v.(map[string]interface{})["a"].(map[string]interface{})
+	b := a["b"].([]interface{})
+	if len(b) != 1 || b[0] != "v" {
+		t.Fatalf("got %v, want [v]", b)
+	}
+}
+
+// TestRemoveConfigPathFromSequence is a regression test for the removal counterpart of the
+// append bug above: removing an element from a nested sequence must write the shortened slice
+// back into its parent.
+func TestRemoveConfigPathFromSequence(t *testing.T) {
+	root := &genNode{v: map[string]interface{}{
+		"servers": []interface{}{"a", "b", "c"},
+	}}
+
+	if err := removeConfigPath(root, []interface{}{"servers", 1}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	servers := root.v.(map[string]interface{})["servers"].([]interface{})
+	if len(servers) != 2 || servers[0] != "a" || servers[1] != "c" {
+		t.Fatalf("got servers %v, want [a c]", servers)
+	}
+}
+
+func TestSetConfigPathShapeConflict(t *testing.T) {
+	root := &genNode{v: map[string]interface{}{
+		"servers": "not-a-map",
+	}}
+
+	err := setConfigPath(root, genNodeFactory{}, []interface{}{"servers", "name"}, config.NewValue("v"))
+	if err != nil {
+		t.Fatalf("scalar values are always replaceable with a container, got error: %v", err)
+	}
+}
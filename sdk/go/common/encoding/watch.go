@@ -0,0 +1,244 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package encoding
+
+import (
+	"os"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/pkg/errors"
+	"github.com/pulumi/go-yaml/ast"
+	"github.com/pulumi/pulumi/sdk/v2/go/common/resource"
+)
+
+// ChangeKind classifies a single config value change reported by a Watcher.
+type ChangeKind int
+
+const (
+	// ChangeAdded indicates a config value that did not exist before and now does.
+	ChangeAdded ChangeKind = iota
+	// ChangeRemoved indicates a config value that existed before and no longer does.
+	ChangeRemoved
+	// ChangeModified indicates a config value whose contents changed.
+	ChangeModified
+)
+
+// ConfigChangeEvent describes a single config value that changed on disk, as computed by diffing
+// the previous AST against the newly re-parsed one.
+type ConfigChangeEvent struct {
+	Path resource.PropertyPath
+	Kind ChangeKind
+}
+
+// Watcher wraps a FileAST, re-parsing it whenever the backing file changes on disk and
+// publishing the set of config paths that were added, removed, or modified. This lets
+// long-running components like `pulumi watch` and the language host live-update configuration
+// for a stack without restarting, and lets plugins react to secret rotation by re-reading only
+// the affected keys.
+type Watcher struct {
+	path  string
+	quiet time.Duration
+
+	mu      sync.Mutex
+	current *FileAST
+
+	events chan ConfigChangeEvent
+	done   chan struct{}
+}
+
+// NewWatcher starts watching path, using quiet as the debounce period for coalescing editors
+// that write a file more than once per save. If fsnotify can't watch path -- e.g. on a filesystem
+// where inotify is unavailable -- NewWatcher falls back to polling at the same interval.
+func NewWatcher(path string, quiet time.Duration) (*Watcher, error) {
+	initial, err := readFileAST(path)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &Watcher{
+		path:    path,
+		quiet:   quiet,
+		current: initial,
+		events:  make(chan ConfigChangeEvent, 16),
+		done:    make(chan struct{}),
+	}
+
+	notify, err := fsnotify.NewWatcher()
+	if err != nil {
+		go w.poll()
+		return w, nil
+	}
+	if err := notify.Add(path); err != nil {
+		notify.Close()
+		go w.poll()
+		return w, nil
+	}
+
+	go w.watch(notify)
+	return w, nil
+}
+
+// Subscribe returns the channel of ConfigChangeEvents for this Watcher. The channel is closed
+// once Close is called.
+func (w *Watcher) Subscribe() <-chan ConfigChangeEvent {
+	return w.events
+}
+
+// Close stops watching the underlying file and closes the event channel.
+func (w *Watcher) Close() {
+	close(w.done)
+}
+
+func readFileAST(path string) (*FileAST, error) {
+	bytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read %q", path)
+	}
+	return NewFileAST(bytes)
+}
+
+// watch runs the fsnotify-backed event loop, debouncing bursts of writes into a single reload.
+// The debounce timer fires into this same goroutine's select (rather than via time.AfterFunc,
+// which would run reload on its own goroutine) so that reload's sends on w.events and this loop's
+// close(w.events) never execute concurrently.
+func (w *Watcher) watch(notify *fsnotify.Watcher) {
+	defer notify.Close()
+
+	timer := time.NewTimer(time.Hour)
+	timer.Stop()
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-w.done:
+			close(w.events)
+			return
+		case ev, ok := <-notify.Events:
+			if !ok {
+				close(w.events)
+				return
+			}
+			if ev.Op&(fsnotify.Write|fsnotify.Create) == 0 {
+				continue
+			}
+			timer.Reset(w.quiet)
+		case <-notify.Errors:
+			continue
+		case <-timer.C:
+			w.reload()
+		}
+	}
+}
+
+// poll is the fallback event loop used when fsnotify can't watch the file directly.
+func (w *Watcher) poll() {
+	ticker := time.NewTicker(w.quiet)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.done:
+			close(w.events)
+			return
+		case <-ticker.C:
+			w.reload()
+		}
+	}
+}
+
+// reload re-parses the file, diffs it against the previous AST, and publishes one
+// ConfigChangeEvent per path that was added, removed, or modified. reload only ever runs on the
+// same goroutine as the loop in watch/poll that may close w.events, so it selects on w.done
+// around every send: if the watcher is closed while a send is blocked on a full buffer, reload
+// gives up rather than risk a send racing the close, or blocking forever with no one left to
+// drain the channel.
+func (w *Watcher) reload() {
+	updated, err := readFileAST(w.path)
+	if err != nil {
+		return
+	}
+
+	w.mu.Lock()
+	previous := w.current
+	w.current = updated
+	w.mu.Unlock()
+
+	for _, change := range diffFileASTs(previous, updated) {
+		select {
+		case w.events <- change:
+		case <-w.done:
+			return
+		}
+	}
+}
+
+// diffFileASTs computes the ConfigChangeEvents between two versions of a config document by
+// walking both ASTs in lockstep, rather than comparing raw bytes.
+func diffFileASTs(old, new *FileAST) []ConfigChangeEvent {
+	var oldRoot, newRoot ast.Node
+	if !old.IsEmpty() {
+		oldRoot = old.ast.Docs[0].Body
+	}
+	if !new.IsEmpty() {
+		newRoot = new.ast.Docs[0].Body
+	}
+
+	var events []ConfigChangeEvent
+	diffNodes(oldRoot, newRoot, nil, &events)
+	return events
+}
+
+func diffNodes(oldNode, newNode ast.Node, path resource.PropertyPath, events *[]ConfigChangeEvent) {
+	switch {
+	case oldNode == nil && newNode == nil:
+		return
+	case oldNode == nil:
+		*events = append(*events, ConfigChangeEvent{Path: append(resource.PropertyPath{}, path...), Kind: ChangeAdded})
+		return
+	case newNode == nil:
+		*events = append(*events, ConfigChangeEvent{Path: append(resource.PropertyPath{}, path...), Kind: ChangeRemoved})
+		return
+	}
+
+	oldMap, oldIsMap := oldNode.(*ast.MappingNode)
+	newMap, newIsMap := newNode.(*ast.MappingNode)
+	if oldIsMap && newIsMap {
+		seen := map[string]bool{}
+		for _, v := range newMap.Values {
+			key := v.Key.String()
+			seen[key] = true
+
+			var oldChild ast.Node
+			if mv := findValue(oldMap, key); mv != nil {
+				oldChild = mv.Value
+			}
+			diffNodes(oldChild, v.Value, append(path, key), events)
+		}
+		for _, v := range oldMap.Values {
+			key := v.Key.String()
+			if !seen[key] {
+				childPath := append(append(resource.PropertyPath{}, path...), key)
+				*events = append(*events, ConfigChangeEvent{Path: childPath, Kind: ChangeRemoved})
+			}
+		}
+		return
+	}
+
+	if oldNode.String() != newNode.String() {
+		*events = append(*events, ConfigChangeEvent{Path: append(resource.PropertyPath{}, path...), Kind: ChangeModified})
+	}
+}
@@ -0,0 +1,103 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package encoding
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDiffFileASTsReportsAddedAndModified(t *testing.T) {
+	oldAST, err := NewFileAST([]byte("a: 1\nb: 2\n"))
+	if err != nil {
+		t.Fatalf("failed to parse old AST: %v", err)
+	}
+	newAST, err := NewFileAST([]byte("a: 1\nb: 3\nc: 4\n"))
+	if err != nil {
+		t.Fatalf("failed to parse new AST: %v", err)
+	}
+
+	byPath := map[string]ChangeKind{}
+	for _, e := range diffFileASTs(oldAST, newAST) {
+		byPath[fmt.Sprintf("%v", e.Path)] = e.Kind
+	}
+
+	if byPath["[b]"] != ChangeModified {
+		t.Fatalf("expected b to be reported modified, got %+v", byPath)
+	}
+	if byPath["[c]"] != ChangeAdded {
+		t.Fatalf("expected c to be reported added, got %+v", byPath)
+	}
+	if _, ok := byPath["[a]"]; ok {
+		t.Fatalf("a did not change and should not be reported, got %+v", byPath)
+	}
+}
+
+func TestDiffFileASTsReportsRemoved(t *testing.T) {
+	oldAST, err := NewFileAST([]byte("a: 1\nb: 2\n"))
+	if err != nil {
+		t.Fatalf("failed to parse old AST: %v", err)
+	}
+	newAST, err := NewFileAST([]byte("a: 1\n"))
+	if err != nil {
+		t.Fatalf("failed to parse new AST: %v", err)
+	}
+
+	events := diffFileASTs(oldAST, newAST)
+	if len(events) != 1 || events[0].Kind != ChangeRemoved {
+		t.Fatalf("got %+v, want a single ChangeRemoved event", events)
+	}
+}
+
+// TestReloadDoesNotBlockAfterClose is a regression test: reload used to do a plain blocking send
+// on w.events with no regard for whether the watcher had been closed. With no reader draining
+// the channel, a pending reload would either block forever (in the polling path, starving the
+// very loop that would have noticed Close) or race the loop's close(w.events) and panic (in the
+// fsnotify path). reload must give up as soon as done is closed instead.
+func TestReloadDoesNotBlockAfterClose(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "Pulumi.yaml")
+	if err := os.WriteFile(path, []byte("a: 1\nb: 2\n"), 0o600); err != nil {
+		t.Fatalf("failed to write test file: %v", err)
+	}
+
+	initial, err := NewFileAST([]byte("a: 1\n"))
+	if err != nil {
+		t.Fatalf("failed to parse initial AST: %v", err)
+	}
+
+	w := &Watcher{
+		path:    path,
+		quiet:   time.Millisecond,
+		current: initial,
+		events:  make(chan ConfigChangeEvent), // unbuffered, with no reader.
+		done:    make(chan struct{}),
+	}
+	close(w.done)
+
+	reloadDone := make(chan struct{})
+	go func() {
+		w.reload()
+		close(reloadDone)
+	}()
+
+	select {
+	case <-reloadDone:
+	case <-time.After(5 * time.Second):
+		t.Fatal("reload blocked forever after Close; it should give up once done is closed")
+	}
+}